@@ -0,0 +1,564 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+)
+
+// Demux modes accepted by the -demux flag.
+const (
+	DemuxNative = "native"
+	DemuxFFmpeg = "ffmpeg"
+	DemuxAuto   = "auto"
+)
+
+// errUnsupportedCodec is wrapped by demuxNative/decodeAudioTrack when the
+// audio track's container or codec isn't implemented natively. Currently
+// only uncompressed 16-bit PCM ('twos'/'sowt') in an MP4/MOV container is
+// decoded in-process. AAC ('mp4a') is recognized (see aacSampleDecoder) but
+// not decoded — that needs a full Huffman/MDCT bitstream decoder, which is
+// out of scope here — and Matroska/WebM isn't parsed at all; both fall back
+// to ffmpeg under -demux auto.
+var errUnsupportedCodec = errors.New("unsupported codec for native demux")
+
+// errNotAudioTrack marks a trak box that isn't a sound track, so
+// demuxNative can keep looking rather than treating it as a hard failure.
+var errNotAudioTrack = errors.New("not an audio track")
+
+// loadAudioSamples decodes the audio track of inputFile into normalized
+// mono float64 samples, using the requested demux mode. "native" avoids
+// ever shelling out to ffmpeg, but only understands uncompressed PCM in an
+// MP4/MOV container — in practice, most real-world MP4/MOV/M4A files carry
+// AAC audio, which native demux recognizes and then declines to decode (see
+// errUnsupportedCodec), so "native" fails and "auto" falls back to ffmpeg
+// for them. "auto" tries native first and falls back to ffmpeg on any
+// other container or codec.
+func loadAudioSamples(analyzer *AudioAnalyzer, inputFile, demuxMode string) ([]float64, float64, error) {
+	switch demuxMode {
+	case DemuxNative:
+		return demuxNative(inputFile)
+	case DemuxAuto:
+		samples, sampleRate, err := demuxNative(inputFile)
+		if err == nil {
+			return samples, sampleRate, nil
+		}
+		log.Printf("Native demux unavailable (%v), falling back to ffmpeg", err)
+		return loadAudioViaFFmpeg(analyzer, inputFile)
+	case DemuxFFmpeg:
+		return loadAudioViaFFmpeg(analyzer, inputFile)
+	default:
+		return nil, 0, fmt.Errorf("unknown -demux mode %q (want native, ffmpeg, or auto)", demuxMode)
+	}
+}
+
+// loadAudioViaFFmpeg extracts audio to a temporary WAV file with ffmpeg and
+// decodes it, mirroring the tool's original behavior.
+func loadAudioViaFFmpeg(analyzer *AudioAnalyzer, inputFile string) ([]float64, float64, error) {
+	audioFile, err := extractAudio(inputFile)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer os.Remove(audioFile)
+
+	return analyzer.LoadAudio(audioFile)
+}
+
+// demuxNative parses inputFile's container directly, locates its audio
+// track, and decodes it to samples without invoking ffmpeg.
+func demuxNative(inputFile string) ([]float64, float64, error) {
+	file, err := os.Open(inputFile)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	if isMatroska(file) {
+		return nil, 0, fmt.Errorf("%w: matroska/webm native demux not implemented", errUnsupportedCodec)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	topLevel, err := readBoxes(file, 0, stat.Size())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	moov, ok := findBox(topLevel, "moov")
+	if !ok {
+		return nil, 0, fmt.Errorf("no moov box found (not a valid MP4/MOV file)")
+	}
+
+	traks, err := readBoxes(file, moov.bodyStart, moov.end)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, trak := range traks {
+		if trak.fourcc != "trak" {
+			continue
+		}
+
+		samples, sampleRate, err := decodeAudioTrack(file, trak)
+		if err == nil {
+			return samples, sampleRate, nil
+		}
+		if !errors.Is(err, errNotAudioTrack) {
+			// This was the audio track; a parse/codec error here is fatal
+			// rather than something another trak could recover from.
+			return nil, 0, err
+		}
+	}
+
+	return nil, 0, fmt.Errorf("no supported audio track found")
+}
+
+// isMatroska reports whether file starts with the EBML magic number used
+// by both Matroska and WebM.
+func isMatroska(file *os.File) bool {
+	header := make([]byte, 4)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return false
+	}
+	return header[0] == 0x1A && header[1] == 0x45 && header[2] == 0xDF && header[3] == 0xA3
+}
+
+// mp4Box is one ISO base media box: its fourcc, and the byte ranges of its
+// header-inclusive extent (start/end) and its body (bodyStart/end).
+type mp4Box struct {
+	fourcc    string
+	start     int64
+	bodyStart int64
+	end       int64
+}
+
+// readBoxes walks the sibling boxes in [start, end) of r, one level deep.
+func readBoxes(r io.ReaderAt, start, end int64) ([]mp4Box, error) {
+	var boxes []mp4Box
+
+	offset := start
+	for offset < end {
+		header := make([]byte, 8)
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		fourcc := string(header[4:8])
+		bodyStart := offset + 8
+
+		switch size {
+		case 1:
+			// 64-bit "largesize" extension.
+			ext := make([]byte, 8)
+			if _, err := r.ReadAt(ext, offset+8); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(ext))
+			bodyStart = offset + 16
+		case 0:
+			// Box extends to the end of its parent.
+			size = end - offset
+		}
+		if size < bodyStart-offset {
+			return nil, fmt.Errorf("invalid box size for %q at offset %d", fourcc, offset)
+		}
+
+		boxes = append(boxes, mp4Box{fourcc: fourcc, start: offset, bodyStart: bodyStart, end: offset + size})
+		offset += size
+	}
+
+	return boxes, nil
+}
+
+// findBox returns the first box with the given fourcc.
+func findBox(boxes []mp4Box, fourcc string) (mp4Box, bool) {
+	for _, b := range boxes {
+		if b.fourcc == fourcc {
+			return b, true
+		}
+	}
+	return mp4Box{}, false
+}
+
+// decodeAudioTrack walks a single trak box's mdia/minf/stbl tree, and, if
+// it's a sound track with a supported sample format, decodes it to
+// normalized mono samples.
+func decodeAudioTrack(file *os.File, trak mp4Box) ([]float64, float64, error) {
+	trakChildren, err := readBoxes(file, trak.bodyStart, trak.end)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	mdia, ok := findBox(trakChildren, "mdia")
+	if !ok {
+		return nil, 0, errNotAudioTrack
+	}
+	mdiaChildren, err := readBoxes(file, mdia.bodyStart, mdia.end)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hdlr, ok := findBox(mdiaChildren, "hdlr")
+	if !ok {
+		return nil, 0, errNotAudioTrack
+	}
+	handlerType := make([]byte, 4)
+	if _, err := file.ReadAt(handlerType, hdlr.bodyStart+8); err != nil {
+		return nil, 0, err
+	}
+	if string(handlerType) != "soun" {
+		return nil, 0, errNotAudioTrack
+	}
+
+	minf, ok := findBox(mdiaChildren, "minf")
+	if !ok {
+		return nil, 0, fmt.Errorf("audio track missing minf box")
+	}
+	minfChildren, err := readBoxes(file, minf.bodyStart, minf.end)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stbl, ok := findBox(minfChildren, "stbl")
+	if !ok {
+		return nil, 0, fmt.Errorf("audio track missing stbl box")
+	}
+	stblChildren, err := readBoxes(file, stbl.bodyStart, stbl.end)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stsd, ok := findBox(stblChildren, "stsd")
+	if !ok {
+		return nil, 0, fmt.Errorf("audio track missing stsd box")
+	}
+	format, channels, bitsPerSample, sampleRate, err := parseStsd(file, stsd)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	decoder, ok := sampleDecoders[format]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %q", errUnsupportedCodec, format)
+	}
+
+	stsz, ok := findBox(stblChildren, "stsz")
+	if !ok {
+		return nil, 0, fmt.Errorf("audio track missing stsz box")
+	}
+	fixedSize, sizes, err := parseStsz(file, stsz)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stsc, ok := findBox(stblChildren, "stsc")
+	if !ok {
+		return nil, 0, fmt.Errorf("audio track missing stsc box")
+	}
+	stscEntries, err := parseStsc(file, stsc)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	chunkBox, ok := findBox(stblChildren, "stco")
+	if !ok {
+		chunkBox, ok = findBox(stblChildren, "co64")
+	}
+	if !ok {
+		return nil, 0, fmt.Errorf("audio track missing stco/co64 box")
+	}
+	chunkOffsets, err := parseChunkOffsets(file, chunkBox)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	chunks, err := chunkLayout(chunkOffsets, stscEntries, fixedSize, sizes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	samples, err := decodeChunks(file, chunks, decoder, channels, bitsPerSample)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return samples, sampleRate, nil
+}
+
+// parseStsd reads the first sample entry of a "stsd" box: its format
+// fourcc and the fields of a QuickTime sound sample description (version
+// 0) that we need to decode PCM.
+func parseStsd(file io.ReaderAt, stsd mp4Box) (format string, channels, bitsPerSample int, sampleRate float64, err error) {
+	header := make([]byte, 8)
+	if _, err = file.ReadAt(header, stsd.bodyStart); err != nil {
+		return
+	}
+	if entryCount := binary.BigEndian.Uint32(header[4:8]); entryCount == 0 {
+		err = fmt.Errorf("stsd has no sample entries")
+		return
+	}
+
+	entryStart := stsd.bodyStart + 8
+
+	entryHeader := make([]byte, 8)
+	if _, err = file.ReadAt(entryHeader, entryStart); err != nil {
+		return
+	}
+	format = string(entryHeader[4:8])
+
+	// Sound sample description fields after the 8-byte entry header:
+	// reserved[6] data_ref_index[2] version[2] revision[2] vendor[4]
+	// num_channels[2] sample_size[2] compression_id[2] packet_size[2]
+	// sample_rate[4] (16.16 fixed point).
+	desc := make([]byte, 28)
+	if _, err = file.ReadAt(desc, entryStart+8); err != nil {
+		return
+	}
+	channels = int(binary.BigEndian.Uint16(desc[16:18]))
+	bitsPerSample = int(binary.BigEndian.Uint16(desc[18:20]))
+	sampleRate = float64(binary.BigEndian.Uint32(desc[24:28])) / 65536.0
+
+	return
+}
+
+// parseStsz reads a "stsz" box. When fixedSize is non-zero, every sample
+// has that size and sizes is nil; otherwise sizes holds one entry per
+// sample.
+func parseStsz(file io.ReaderAt, stsz mp4Box) (fixedSize uint32, sizes []uint32, err error) {
+	header := make([]byte, 12)
+	if _, err = file.ReadAt(header, stsz.bodyStart); err != nil {
+		return
+	}
+
+	fixedSize = binary.BigEndian.Uint32(header[4:8])
+	if fixedSize != 0 {
+		return fixedSize, nil, nil
+	}
+
+	sampleCount := binary.BigEndian.Uint32(header[8:12])
+	buf := make([]byte, sampleCount*4)
+	if _, err = file.ReadAt(buf, stsz.bodyStart+12); err != nil {
+		return
+	}
+
+	sizes = make([]uint32, sampleCount)
+	for i := range sizes {
+		sizes[i] = binary.BigEndian.Uint32(buf[i*4 : i*4+4])
+	}
+	return
+}
+
+// stscEntry is one "sample-to-chunk" table entry: from firstChunk onward,
+// each chunk holds samplesPerChunk samples.
+type stscEntry struct {
+	firstChunk      uint32
+	samplesPerChunk uint32
+}
+
+func parseStsc(file io.ReaderAt, stsc mp4Box) ([]stscEntry, error) {
+	header := make([]byte, 8)
+	if _, err := file.ReadAt(header, stsc.bodyStart); err != nil {
+		return nil, err
+	}
+
+	count := binary.BigEndian.Uint32(header[4:8])
+	buf := make([]byte, count*12)
+	if _, err := file.ReadAt(buf, stsc.bodyStart+8); err != nil {
+		return nil, err
+	}
+
+	entries := make([]stscEntry, count)
+	for i := range entries {
+		off := i * 12
+		entries[i] = stscEntry{
+			firstChunk:      binary.BigEndian.Uint32(buf[off : off+4]),
+			samplesPerChunk: binary.BigEndian.Uint32(buf[off+4 : off+8]),
+		}
+	}
+	return entries, nil
+}
+
+// parseChunkOffsets reads a "stco" (32-bit) or "co64" (64-bit) box into a
+// list of chunk byte offsets.
+func parseChunkOffsets(file io.ReaderAt, box mp4Box) ([]int64, error) {
+	header := make([]byte, 8)
+	if _, err := file.ReadAt(header, box.bodyStart); err != nil {
+		return nil, err
+	}
+	count := binary.BigEndian.Uint32(header[4:8])
+
+	offsets := make([]int64, count)
+	if box.fourcc == "co64" {
+		buf := make([]byte, count*8)
+		if _, err := file.ReadAt(buf, box.bodyStart+8); err != nil {
+			return nil, err
+		}
+		for i := range offsets {
+			offsets[i] = int64(binary.BigEndian.Uint64(buf[i*8 : i*8+8]))
+		}
+	} else {
+		buf := make([]byte, count*4)
+		if _, err := file.ReadAt(buf, box.bodyStart+8); err != nil {
+			return nil, err
+		}
+		for i := range offsets {
+			offsets[i] = int64(binary.BigEndian.Uint32(buf[i*4 : i*4+4]))
+		}
+	}
+	return offsets, nil
+}
+
+// chunkRange is one chunk's byte range in the source file. Samples within
+// a chunk are stored contiguously, so the whole range can be read (and,
+// for PCM, decoded) in a single operation instead of one per sample.
+type chunkRange struct {
+	offset int64
+	size   int64
+}
+
+// chunkLayout combines a track's chunk offsets, sample-to-chunk table, and
+// sample sizes into one byte range per chunk, in time order. Samples are
+// only consulted to sum each chunk's size, not to address them
+// individually: for PCM tracks, stsz commonly lists one entry per 2-4 byte
+// audio frame, so reading (and decoding) sample-by-sample would mean
+// millions of ReadAt calls for a short clip.
+func chunkLayout(chunkOffsets []int64, stsc []stscEntry, fixedSampleSize uint32, sizes []uint32) ([]chunkRange, error) {
+	var chunks []chunkRange
+
+	sampleIndex := 0
+	for chunkIdx, chunkOffset := range chunkOffsets {
+		samplesInChunk := samplesPerChunkAt(stsc, uint32(chunkIdx+1))
+
+		var size int64
+		if fixedSampleSize != 0 {
+			size = int64(fixedSampleSize) * int64(samplesInChunk)
+		} else {
+			for s := uint32(0); s < samplesInChunk; s++ {
+				if sampleIndex >= len(sizes) {
+					return nil, fmt.Errorf("stsc/stsz mismatch: chunk %d expects more samples than stsz lists", chunkIdx+1)
+				}
+				size += int64(sizes[sampleIndex])
+				sampleIndex++
+			}
+		}
+
+		chunks = append(chunks, chunkRange{offset: chunkOffset, size: size})
+	}
+
+	return chunks, nil
+}
+
+// samplesPerChunkAt returns the samples-per-chunk value in effect for the
+// given 1-based chunk number, per the "stsc" table's run-length encoding.
+func samplesPerChunkAt(stsc []stscEntry, chunkNum uint32) uint32 {
+	samplesPerChunk := uint32(1)
+	for _, entry := range stsc {
+		if chunkNum < entry.firstChunk {
+			break
+		}
+		samplesPerChunk = entry.samplesPerChunk
+	}
+	return samplesPerChunk
+}
+
+// sampleDecoder decodes one chunk's raw sample bytes into mono float64 PCM
+// frames. Looked up by stsd format fourcc in sampleDecoders, so adding
+// native support for a new codec means registering a decoder here rather
+// than touching decodeAudioTrack's control flow.
+type sampleDecoder interface {
+	decode(raw []byte, channels, bitsPerSample int) ([]float64, error)
+}
+
+// sampleDecoders maps an stsd sample entry's format fourcc to the decoder
+// that understands it.
+var sampleDecoders = map[string]sampleDecoder{
+	"sowt": pcmSampleDecoder{littleEndian: true},
+	"twos": pcmSampleDecoder{littleEndian: false},
+	"mp4a": aacSampleDecoder{},
+}
+
+// pcmSampleDecoder decodes uncompressed interleaved PCM, downmixing to
+// mono by averaging channels (matching the rest of the analyzer, which
+// works in mono).
+type pcmSampleDecoder struct {
+	littleEndian bool
+}
+
+func (d pcmSampleDecoder) decode(raw []byte, channels, bitsPerSample int) ([]float64, error) {
+	if bitsPerSample != 16 {
+		return nil, fmt.Errorf("%w: %d-bit PCM (only 16-bit is supported natively)", errUnsupportedCodec, bitsPerSample)
+	}
+
+	bytesPerSample := bitsPerSample / 8
+	bytesPerFrame := channels * bytesPerSample
+	if bytesPerFrame == 0 {
+		return nil, fmt.Errorf("invalid PCM frame size")
+	}
+
+	maxValue := math.Pow(2, float64(bitsPerSample-1))
+
+	frames := len(raw) / bytesPerFrame
+	samples := make([]float64, 0, frames)
+	for f := 0; f < frames; f++ {
+		frameOffset := f * bytesPerFrame
+		sum := 0.0
+		for c := 0; c < channels; c++ {
+			byteOffset := frameOffset + c*bytesPerSample
+			var sample int16
+			if d.littleEndian {
+				sample = int16(binary.LittleEndian.Uint16(raw[byteOffset : byteOffset+2]))
+			} else {
+				sample = int16(binary.BigEndian.Uint16(raw[byteOffset : byteOffset+2]))
+			}
+			sum += float64(sample) / maxValue
+		}
+		samples = append(samples, sum/float64(channels))
+	}
+
+	return samples, nil
+}
+
+// aacSampleDecoder exists so 'mp4a' tracks are recognized (and rejected
+// with a precise, wrapped errUnsupportedCodec) instead of being
+// misdiagnosed as some other codec. It does not actually decode AAC: that
+// needs a full Huffman/MDCT bitstream decoder, which is out of scope for
+// this native demuxer. Every call fails, so -demux auto falls back to
+// ffmpeg for AAC sources — the vast majority of real MP4/MOV/M4A files.
+type aacSampleDecoder struct{}
+
+func (aacSampleDecoder) decode(raw []byte, channels, bitsPerSample int) ([]float64, error) {
+	return nil, fmt.Errorf("%w: AAC decoding is not implemented natively", errUnsupportedCodec)
+}
+
+// decodeChunks reads each chunk's byte range in a single ReadAt and hands
+// it to decoder, concatenating the resulting mono samples in time order.
+func decodeChunks(file io.ReaderAt, chunks []chunkRange, decoder sampleDecoder, channels, bitsPerSample int) ([]float64, error) {
+	var samples []float64
+	var buf []byte
+
+	for _, chunk := range chunks {
+		if cap(buf) < int(chunk.size) {
+			buf = make([]byte, chunk.size)
+		}
+		buf = buf[:chunk.size]
+		if _, err := file.ReadAt(buf, chunk.offset); err != nil {
+			return nil, err
+		}
+
+		decoded, err := decoder.decode(buf, channels, bitsPerSample)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, decoded...)
+	}
+
+	return samples, nil
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 
@@ -42,14 +43,33 @@ type AudioAnalyzer struct {
 	SampleRate     int
 }
 
+// Detector finds excitement markers in a stream of audio samples. Different
+// implementations trade off sensitivity to pure loudness spikes (RMS)
+// against onset detection (spectral flux) and speech/laughter discrimination
+// (zero-crossing rate), but all produce the same ExcitementMarker output so
+// mergeOverlappingMarkers and exportToLosslessCut don't need to know which
+// detector(s) produced them.
+type Detector interface {
+	Analyze(samples []float64, sampleRate float64) []ExcitementMarker
+}
+
 func main() {
 	var (
-		inputFile   = flag.String("input", "", "Input video file path")
-		outputFile  = flag.String("output", "", "Output LosslessCut project file path (default: input_name.proj.llc)")
-		threshold   = flag.Float64("threshold", 2.0, "Volume spike threshold multiplier")
-		minDuration = flag.Float64("min-duration", 1.0, "Minimum excitement duration in seconds")
-		windowMs    = flag.Int("window", 1000, "Analysis window size in milliseconds")
-		verbose     = flag.Bool("verbose", false, "Enable verbose logging")
+		inputFile    = flag.String("input", "", "Input video file path")
+		outputFile   = flag.String("output", "", "Output LosslessCut project file path (default: input_name.proj.llc)")
+		threshold    = flag.Float64("threshold", 2.0, "Volume spike threshold multiplier")
+		minDuration  = flag.Float64("min-duration", 1.0, "Minimum excitement duration in seconds")
+		windowMs     = flag.Int("window", 1000, "Analysis window size in milliseconds")
+		verbose      = flag.Bool("verbose", false, "Enable verbose logging")
+		detectorFlag    = flag.String("detector", "rms", "Excitement detector(s) to use: rms, flux, zcr (comma-separated to combine, e.g. \"rms,flux,zcr\")")
+		fluxK           = flag.Float64("flux-k", 1.5, "MAD multiplier above the median flux for the spectral flux detector's onset threshold")
+		zcrThreshold    = flag.Float64("zcr-threshold", 0.15, "Zero-crossing rate above which a high-energy window is classified as laughter/applause rather than shouting/music")
+		demuxMode       = flag.String("demux", DemuxAuto, "How to obtain audio from the input: native (in-process MP4/MOV demux, PCM only), ffmpeg (always works), or auto (try native, fall back to ffmpeg)")
+		workers         = flag.Int("workers", runtime.NumCPU(), "Worker goroutines for streaming RMS computation (-detector rms with -demux ffmpeg)")
+		mode            = flag.String("mode", "cut", "Export mode: cut (segments to remove) or keep (excitement regions themselves, for a highlight reel)")
+		preRoll         = flag.Float64("pre-roll", 0, "Seconds to expand each kept segment before its start (keep mode only)")
+		postRoll        = flag.Float64("post-roll", 0, "Seconds to expand each kept segment after its end (keep mode only)")
+		snapToKeyframes = flag.Bool("snap-to-keyframes", false, "Snap segment boundaries to the nearest keyframe so LosslessCut's stream-copy export is frame-accurate")
 	)
 	flag.Parse()
 
@@ -74,30 +94,73 @@ func main() {
 	fmt.Printf("Processing video: %s\n", *inputFile)
 	fmt.Printf("Output file: %s\n", *outputFile)
 
-	// Extract audio from video
-	audioFile, err := extractAudio(*inputFile)
-	if err != nil {
-		log.Fatalf("Failed to extract audio: %v", err)
-	}
-	defer os.Remove(audioFile) // Clean up temp audio file
-
-	// Analyze audio for excitement markers
+	// Analyze audio for excitement markers. WindowSize/SampleRate assume
+	// 44.1kHz until the actual audio is loaded below; -demux native can
+	// return a different native container rate, at which point WindowSize
+	// is recomputed from it.
 	analyzer := &AudioAnalyzer{
-		WindowSize:     *windowMs * 44100 / 1000, // Convert ms to samples (44.1kHz)
+		WindowSize:     *windowMs * 44100 / 1000,
 		ThresholdRatio: *threshold,
 		MinDuration:    *minDuration,
 		SampleRate:     44100,
 	}
 
-	markers, err := analyzer.AnalyzeAudio(audioFile)
-	if err != nil {
-		log.Fatalf("Failed to analyze audio: %v", err)
+	var markers []ExcitementMarker
+	var err error
+
+	// The plain RMS detector sourced from ffmpeg's WAV output can run
+	// through the streaming pipeline: it never materializes the full
+	// decoded sample slab, so multi-hour recordings process in bounded
+	// memory. Any other combination (flux/zcr need the full buffer for
+	// FFT framing and lookback, and the native demuxer doesn't produce a
+	// WAV file to stream from) uses the regular buffered path.
+	if *detectorFlag == "rms" && *demuxMode == DemuxFFmpeg {
+		markers, err = streamingRMSMarkers(analyzer, *inputFile, *workers)
+		if err != nil {
+			log.Fatalf("Failed to extract audio: %v", err)
+		}
+	} else {
+		// Obtain decoded audio, either via the in-process demuxer or by
+		// shelling out to ffmpeg, per -demux.
+		samples, sampleRate, err := loadAudioSamples(analyzer, *inputFile, *demuxMode)
+		if err != nil {
+			log.Fatalf("Failed to extract audio: %v", err)
+		}
+
+		// The native demuxer doesn't resample, so it can hand back audio at
+		// whatever rate the container stores (e.g. 48kHz); the ffmpeg path
+		// always forces 44.1kHz. Recompute WindowSize from the real rate so
+		// -window ms means the same thing regardless of -demux.
+		analyzer.SampleRate = int(sampleRate)
+		analyzer.WindowSize = *windowMs * int(sampleRate) / 1000
+
+		detectors, err := buildDetectors(*detectorFlag, analyzer, *fluxK, *zcrThreshold)
+		if err != nil {
+			log.Fatalf("Invalid -detector value: %v", err)
+		}
+
+		for _, detector := range detectors {
+			markers = append(markers, detector.Analyze(samples, sampleRate)...)
+		}
 	}
 
 	cleanedUpMarkers := mergeOverlappingMarkers(markers)
 
-	// Export markers to LosslessCut JSON format
-	err = exportToLosslessCut(cleanedUpMarkers, *outputFile, filepath.Base(*inputFile))
+	segments, err := buildCutSegments(cleanedUpMarkers, *mode, *preRoll, *postRoll)
+	if err != nil {
+		log.Fatalf("Invalid -mode value: %v", err)
+	}
+
+	if *snapToKeyframes {
+		keyframes, err := loadOrProbeKeyframes(*inputFile)
+		if err != nil {
+			log.Fatalf("Failed to determine keyframe timestamps: %v", err)
+		}
+		segments = snapSegmentsToKeyframes(segments, keyframes)
+	}
+
+	// Export segments to LosslessCut JSON format
+	err = exportToLosslessCut(segments, *outputFile, filepath.Base(*inputFile))
 	if err != nil {
 		log.Fatalf("Failed to export markers: %v", err)
 	}
@@ -134,23 +197,25 @@ func extractAudio(videoFile string) (string, error) {
 	return audioFile, nil
 }
 
-func (a *AudioAnalyzer) AnalyzeAudio(audioFile string) ([]ExcitementMarker, error) {
+// LoadAudio decodes a WAV file into normalized float64 samples in [-1, 1]
+// ready for any Detector to analyze.
+func (a *AudioAnalyzer) LoadAudio(audioFile string) ([]float64, float64, error) {
 	// Open WAV file
 	file, err := os.Open(audioFile)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer file.Close()
 
 	decoder := wav.NewDecoder(file)
 	if !decoder.IsValidFile() {
-		return nil, fmt.Errorf("invalid WAV file")
+		return nil, 0, fmt.Errorf("invalid WAV file")
 	}
 
 	// Read all audio data
 	audioData, err := decoder.FullPCMBuffer()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Convert integer samples to float64
@@ -169,16 +234,108 @@ func (a *AudioAnalyzer) AnalyzeAudio(audioFile string) ([]ExcitementMarker, erro
 	log.Printf("Audio info: %d samples, %.1f Hz, %.2f seconds",
 		len(samples), sampleRate, float64(len(samples))/sampleRate)
 
-	return a.detectExcitementMarkers(samples, sampleRate), nil
+	return samples, sampleRate, nil
 }
 
-func (a *AudioAnalyzer) detectExcitementMarkers(samples []float64, sampleRate float64) []ExcitementMarker {
+// buildDetectors parses a comma-separated -detector flag value (e.g.
+// "rms,flux,zcr") into the Detector implementations it names.
+func buildDetectors(spec string, a *AudioAnalyzer, fluxK, zcrThreshold float64) ([]Detector, error) {
+	names := strings.Split(spec, ",")
+	detectors := make([]Detector, 0, len(names))
+
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "rms":
+			detectors = append(detectors, &RMSDetector{
+				WindowSize:     a.WindowSize,
+				ThresholdRatio: a.ThresholdRatio,
+				MinDuration:    a.MinDuration,
+			})
+		case "flux":
+			detectors = append(detectors, &FluxDetector{
+				WindowSize:  a.WindowSize,
+				MinDuration: a.MinDuration,
+				K:           fluxK,
+			})
+		case "zcr":
+			detectors = append(detectors, &ZCRDetector{
+				WindowSize:           a.WindowSize,
+				MinDuration:          a.MinDuration,
+				EnergyThresholdRatio: a.ThresholdRatio,
+				ZCRThreshold:         zcrThreshold,
+			})
+		default:
+			return nil, fmt.Errorf("unknown detector %q (want rms, flux, or zcr)", name)
+		}
+	}
+
+	return detectors, nil
+}
+
+// markersFromFlags merges consecutive flagged windows into excitement
+// markers, discarding runs shorter than minDuration. windowSamples is the
+// number of audio samples each window (or hop, for detectors that use
+// overlapping frames) advances per index in flagged.
+func markersFromFlags(flagged []bool, windowSamples int, sampleRate float64, minDuration float64, label func(start, end int) string, score func(start, end int) float64) []ExcitementMarker {
+	var markers []ExcitementMarker
+	var runStart int
+	var inRun bool
+
+	flush := func(end int) {
+		duration := float64(end-runStart) * float64(windowSamples) / sampleRate
+		if duration < minDuration {
+			log.Printf("Skipping short excitement period: %.2fs < %.2fs", duration, minDuration)
+			return
+		}
+
+		marker := ExcitementMarker{
+			StartTime: float64(runStart*windowSamples) / sampleRate,
+			EndTime:   float64(end*windowSamples) / sampleRate,
+			Label:     label(runStart, end),
+			Score:     score(runStart, end),
+		}
+		markers = append(markers, marker)
+		log.Printf("Added marker: %.2fs-%.2fs (%s)", marker.StartTime, marker.EndTime, marker.Label)
+	}
+
+	for i, flag := range flagged {
+		if flag {
+			if !inRun {
+				runStart = i
+				inRun = true
+			}
+		} else if inRun {
+			flush(i)
+			inRun = false
+		}
+	}
+	if inRun {
+		flush(len(flagged))
+	}
+
+	if markers == nil {
+		markers = []ExcitementMarker{}
+	}
+	return markers
+}
+
+// RMSDetector flags windows whose root-mean-square volume exceeds a
+// multiple of the recording's baseline RMS (a median-of-medians estimate,
+// see robustBaseline). It's the original, simplest
+// excitement heuristic: good at catching sustained loudness spikes, but
+// blind to onsets that don't raise average volume much.
+type RMSDetector struct {
+	WindowSize     int     // Window size in samples
+	ThresholdRatio float64 // Multiplier for baseline volume
+	MinDuration    float64 // Minimum duration for a valid marker (seconds)
+}
+
+func (d *RMSDetector) Analyze(samples []float64, sampleRate float64) []ExcitementMarker {
 	if len(samples) == 0 {
 		return []ExcitementMarker{}
 	}
 
-	// Calculate RMS (Root Mean Square) values for sliding windows
-	windowSamples := a.WindowSize
+	windowSamples := d.WindowSize
 	if windowSamples <= 0 {
 		windowSamples = int(sampleRate) // Default to 1 second
 	}
@@ -189,133 +346,318 @@ func (a *AudioAnalyzer) detectExcitementMarkers(samples []float64, sampleRate fl
 	}
 
 	rmsValues := make([]float64, numWindows)
+	for i := 0; i < numWindows; i++ {
+		start := i * windowSamples
+		end := start + windowSamples
+		if end > len(samples) {
+			end = len(samples)
+		}
+		rmsValues[i] = rootMeanSquare(samples[start:end])
+	}
+
+	// Median-of-medians so one sustained loud section can't drag the
+	// baseline upward; kept in sync with the streaming RMS path
+	// (streamingRMSMarkers) so -detector rms behaves the same regardless
+	// of -demux.
+	baseline := robustBaseline(rmsValues, rmsBaselineBlockSize)
+	log.Printf("Baseline RMS: %.6f", baseline)
+
+	threshold := baseline * d.ThresholdRatio
+	log.Printf("Threshold: %.6f (%.1fx baseline)", threshold, d.ThresholdRatio)
 
-	// Calculate RMS for each window
+	flagged := make([]bool, numWindows)
+	for i, rms := range rmsValues {
+		flagged[i] = rms > threshold
+	}
+
+	label := func(start, end int) string {
+		return fmt.Sprintf("Excitement (%.1fx)", averageMultiplier(rmsValues, baseline, start, end))
+	}
+	score := func(start, end int) float64 {
+		return averageMultiplier(rmsValues, baseline, start, end)
+	}
+
+	return markersFromFlags(flagged, windowSamples, sampleRate, d.MinDuration, label, score)
+}
+
+// FluxDetector is a spectral flux onset detector: it takes a Hann-windowed
+// STFT, sums the positive (rising) change in magnitude spectrum between
+// consecutive frames, and flags frames whose flux is a robust outlier
+// (median + k*MAD). Onsets like a cut or a sudden cheer show up as flux
+// spikes even when they don't move the average RMS much.
+type FluxDetector struct {
+	WindowSize  int     // STFT window size in samples; hop is WindowSize/4
+	MinDuration float64 // Minimum duration for a valid marker (seconds)
+	K           float64 // MAD multiplier for the onset threshold
+}
+
+func (d *FluxDetector) Analyze(samples []float64, sampleRate float64) []ExcitementMarker {
+	window := d.WindowSize
+	if window <= 0 {
+		window = int(sampleRate)
+	}
+	hop := window / 4
+	if hop <= 0 {
+		hop = 1
+	}
+
+	var spectra [][]float64
+	for start := 0; start+window <= len(samples); start += hop {
+		frame := make([]float64, window)
+		copy(frame, samples[start:start+window])
+		applyHannWindow(frame)
+		spectra = append(spectra, fftMagnitude(frame))
+	}
+	if len(spectra) < 2 {
+		return []ExcitementMarker{}
+	}
+
+	flux := make([]float64, len(spectra))
+	for i := 1; i < len(spectra); i++ {
+		flux[i] = spectralFlux(spectra[i-1], spectra[i])
+	}
+
+	med := median(flux)
+	mad := medianAbsoluteDeviation(flux, med)
+	threshold := med + d.K*mad
+	log.Printf("Flux baseline median: %.6f, MAD: %.6f, threshold: %.6f", med, mad, threshold)
+
+	flagged := make([]bool, len(flux))
+	for i, f := range flux {
+		flagged[i] = f > threshold
+	}
+
+	label := func(start, end int) string { return "Onset (spectral flux)" }
+	score := func(start, end int) float64 {
+		if med == 0 {
+			return 0
+		}
+		return average(flux[start:end]) / med
+	}
+
+	return markersFromFlags(flagged, hop, sampleRate, d.MinDuration, label, score)
+}
+
+// ZCRDetector combines zero-crossing rate with RMS energy to tell apart
+// different kinds of loud windows: high ZCR alongside high energy reads as
+// laughter or applause (noisy, broadband), while low ZCR with high energy
+// reads as shouting or a music hit (more tonal/periodic).
+type ZCRDetector struct {
+	WindowSize           int     // Window size in samples
+	MinDuration          float64 // Minimum duration for a valid marker (seconds)
+	EnergyThresholdRatio float64 // Multiplier for baseline RMS
+	ZCRThreshold         float64 // Zero-crossing rate that separates the two labels
+}
+
+func (d *ZCRDetector) Analyze(samples []float64, sampleRate float64) []ExcitementMarker {
+	windowSamples := d.WindowSize
+	if windowSamples <= 0 {
+		windowSamples = int(sampleRate)
+	}
+
+	numWindows := len(samples) / windowSamples
+	if numWindows == 0 {
+		return []ExcitementMarker{}
+	}
+
+	rmsValues := make([]float64, numWindows)
+	zcrValues := make([]float64, numWindows)
 	for i := 0; i < numWindows; i++ {
 		start := i * windowSamples
 		end := start + windowSamples
 		if end > len(samples) {
 			end = len(samples)
 		}
+		rmsValues[i] = rootMeanSquare(samples[start:end])
+		zcrValues[i] = zeroCrossingRate(samples[start:end])
+	}
 
-		sum := 0.0
-		for j := start; j < end; j++ {
-			sum += samples[j] * samples[j]
+	baseline := average(rmsValues)
+	threshold := baseline * d.EnergyThresholdRatio
+	log.Printf("ZCR detector baseline RMS: %.6f, energy threshold: %.6f", baseline, threshold)
+
+	flagged := make([]bool, numWindows)
+	for i := range flagged {
+		flagged[i] = rmsValues[i] > threshold
+	}
+
+	label := func(start, end int) string {
+		if average(zcrValues[start:end]) > d.ZCRThreshold {
+			return "Laughter/applause (high ZCR)"
 		}
-		rmsValues[i] = math.Sqrt(sum / float64(end-start))
+		return "Shouting/music hit (low ZCR)"
+	}
+	score := func(start, end int) float64 {
+		return averageMultiplier(rmsValues, baseline, start, end)
+	}
+
+	return markersFromFlags(flagged, windowSamples, sampleRate, d.MinDuration, label, score)
+}
+
+// rootMeanSquare returns the RMS volume of a slice of samples.
+func rootMeanSquare(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
 	}
+	sum := 0.0
+	for _, s := range samples {
+		sum += s * s
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
 
-	// Calculate baseline (average RMS)
-	baseline := 0.0
-	for _, rms := range rmsValues {
-		baseline += rms
+// zeroCrossingRate returns the fraction of adjacent sample pairs in samples
+// that differ in sign.
+func zeroCrossingRate(samples []float64) float64 {
+	if len(samples) < 2 {
+		return 0
 	}
-	baseline /= float64(len(rmsValues))
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] >= 0) != (samples[i] >= 0) {
+			crossings++
+		}
+	}
+	return float64(crossings) / float64(len(samples)-1)
+}
 
-	log.Printf("Baseline RMS: %.6f", baseline)
+// average returns the arithmetic mean of values, or 0 for an empty slice.
+func average(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
 
-	threshold := baseline * a.ThresholdRatio
-	log.Printf("Threshold: %.6f (%.1fx baseline)", threshold, a.ThresholdRatio)
+// averageMultiplier returns the average ratio of values[start:end] to
+// baseline, matching the "Nx baseline" scoring the RMS detector has always
+// reported.
+func averageMultiplier(values []float64, baseline float64, start, end int) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	return average(values[start:end]) / baseline
+}
 
-	// Find excitement periods
-	var markers []ExcitementMarker
-	var excitementStart int
-	var inExcitement bool
+// median returns the median of values. It copies and sorts its input, so
+// the caller's slice is left untouched.
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
 
-	for i, rms := range rmsValues {
-		if rms > threshold {
-			if !inExcitement {
-				// Start of excitement period
-				excitementStart = i
-				inExcitement = true
-				log.Printf("Excitement start at window %d (%.2fs), RMS: %.6f", i, float64(i*windowSamples)/sampleRate, rms)
-			}
-		} else {
-			if inExcitement {
-				// End of excitement period
-				windowDiff := i - excitementStart
-				duration := float64(windowDiff) * float64(windowSamples) / sampleRate
-				log.Printf("Excitement end at window %d (%.2fs), excitementStart: %d, i: %d, windowDiff: %d, windowSamples: %d, sampleRate: %.0f, duration: %.2fs, min required: %.2fs", i, float64(i*windowSamples)/sampleRate, excitementStart, i, windowDiff, windowSamples, sampleRate, duration, a.MinDuration)
-				if duration >= a.MinDuration {
-					startTime := float64(excitementStart*windowSamples) / sampleRate
-					endTime := float64(i*windowSamples) / sampleRate
-
-					// Calculate average multiplier for this segment
-					avgMultiplier := 0.0
-					count := 0
-					for j := excitementStart; j < i; j++ {
-						avgMultiplier += rmsValues[j] / baseline
-						count++
-					}
-					if count > 0 {
-						avgMultiplier /= float64(count)
-					}
-
-					marker := ExcitementMarker{
-						StartTime: startTime,
-						EndTime:   endTime,
-						Label:     fmt.Sprintf("Excitement (%.1fx)", avgMultiplier),
-						Score:     avgMultiplier,
-					}
-					markers = append(markers, marker)
-					log.Printf("Added marker: %.2fs-%.2fs (%.1fx)", startTime, endTime, avgMultiplier)
-				} else {
-					log.Printf("Skipping short excitement period: %.2fs < %.2fs", duration, a.MinDuration)
-				}
-				inExcitement = false
-			}
+// medianAbsoluteDeviation returns the median of |values[i] - center|, a
+// robust, outlier-resistant measure of spread.
+func medianAbsoluteDeviation(values []float64, center float64) float64 {
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - center)
+	}
+	return median(deviations)
+}
+
+// spectralFlux sums the positive change in magnitude spectrum between two
+// consecutive STFT frames: flux = sum(max(0, |X_t[k]| - |X_{t-1}[k]|)).
+func spectralFlux(prev, curr []float64) float64 {
+	flux := 0.0
+	for k := range curr {
+		if diff := curr[k] - prev[k]; diff > 0 {
+			flux += diff
 		}
 	}
+	return flux
+}
 
-	// Handle case where excitement period extends to end of audio
-	if inExcitement {
-		windowDiff := len(rmsValues) - excitementStart
-		duration := float64(windowDiff) * float64(windowSamples) / sampleRate
-		if duration >= a.MinDuration {
-			startTime := float64(excitementStart*windowSamples) / sampleRate
-			endTime := float64(len(samples)) / sampleRate
-
-			// Calculate average multiplier for this segment
-			avgMultiplier := 0.0
-			count := 0
-			for j := excitementStart; j < len(rmsValues); j++ {
-				avgMultiplier += rmsValues[j] / baseline
-				count++
-			}
-			if count > 0 {
-				avgMultiplier /= float64(count)
-			}
+// applyHannWindow multiplies samples in place by a Hann window, tapering
+// the frame edges to reduce spectral leakage before the FFT.
+func applyHannWindow(samples []float64) {
+	n := len(samples)
+	if n < 2 {
+		return
+	}
+	for i := range samples {
+		samples[i] *= 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(n-1)))
+	}
+}
 
-			marker := ExcitementMarker{
-				StartTime: startTime,
-				EndTime:   endTime,
-				Label:     fmt.Sprintf("Excitement (%.1fx)", avgMultiplier),
-				Score:     avgMultiplier,
+// fftMagnitude zero-pads signal to the next power of two and returns the
+// magnitude spectrum (length n/2+1) of its discrete Fourier transform,
+// computed via iterative radix-2 Cooley-Tukey.
+func fftMagnitude(signal []float64) []float64 {
+	n := nextPowerOfTwo(len(signal))
+	re := make([]float64, n)
+	im := make([]float64, n)
+	copy(re, signal)
+
+	bitReverseInPlace(re, im)
+	for size := 2; size <= n; size *= 2 {
+		halfSize := size / 2
+		angleStep := -2 * math.Pi / float64(size)
+		for start := 0; start < n; start += size {
+			for k := 0; k < halfSize; k++ {
+				wr, wi := math.Cos(angleStep*float64(k)), math.Sin(angleStep*float64(k))
+				i1, i2 := start+k, start+k+halfSize
+				tr := re[i2]*wr - im[i2]*wi
+				ti := re[i2]*wi + im[i2]*wr
+				re[i2] = re[i1] - tr
+				im[i2] = im[i1] - ti
+				re[i1] += tr
+				im[i1] += ti
 			}
-			markers = append(markers, marker)
-			log.Printf("Added final marker: %.2fs-%.2fs (%.1fx)", startTime, endTime, avgMultiplier)
 		}
 	}
 
-	return markers
+	magnitudes := make([]float64, n/2+1)
+	for i := range magnitudes {
+		magnitudes[i] = math.Hypot(re[i], im[i])
+	}
+	return magnitudes
 }
 
-func exportToLosslessCut(markers []ExcitementMarker, filename string, mediaFileName string) error {
-	project := LosslessCutProject{
-		Version:       1,
-		MediaFileName: mediaFileName,
-		CutSegments:   make([]CutSegment, len(markers)),
+// bitReverseInPlace permutes re/im into bit-reversed order, the standard
+// first step of an iterative Cooley-Tukey FFT.
+func bitReverseInPlace(re, im []float64) {
+	n := len(re)
+	j := 0
+	for i := 1; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j &^= bit
+		}
+		j |= bit
+		if i < j {
+			re[i], re[j] = re[j], re[i]
+			im[i], im[j] = im[j], im[i]
+		}
 	}
+}
 
-	for i, marker := range markers {
-		start := marker.StartTime
-		end := marker.EndTime
+// nextPowerOfTwo returns the smallest power of two that is >= n.
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p *= 2
+	}
+	return p
+}
 
-		project.CutSegments[i] = CutSegment{
-			Start: start,
-			End:   end,
-			Name:  marker.Label,
-		}
+func exportToLosslessCut(segments []CutSegment, filename string, mediaFileName string) error {
+	project := LosslessCutProject{
+		Version:       1,
+		MediaFileName: mediaFileName,
+		CutSegments:   segments,
 	}
 
 	file, err := os.Create(filename)
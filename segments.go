@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// buildCutSegments turns merged excitement markers into the CutSegments
+// exportToLosslessCut writes out. In "cut" mode a segment is exactly one
+// excitement region, unchanged (the tool's original behavior). In "keep"
+// mode each region is expanded by preRoll/postRoll, for assembling a
+// highlight reel via LosslessCut's "keep selected segments" export.
+func buildCutSegments(markers []ExcitementMarker, mode string, preRoll, postRoll float64) ([]CutSegment, error) {
+	segments := make([]CutSegment, len(markers))
+
+	switch mode {
+	case "cut":
+		for i, marker := range markers {
+			segments[i] = CutSegment{Start: marker.StartTime, End: marker.EndTime, Name: marker.Label}
+		}
+	case "keep":
+		for i, marker := range markers {
+			start := marker.StartTime - preRoll
+			if start < 0 {
+				start = 0
+			}
+			segments[i] = CutSegment{Start: start, End: marker.EndTime + postRoll, Name: marker.Label}
+		}
+	default:
+		return nil, fmt.Errorf("unknown -mode %q (want cut or keep)", mode)
+	}
+
+	return segments, nil
+}
+
+// snapSegmentsToKeyframes snaps each segment's Start down and End up to
+// the nearest keyframe timestamp, so LosslessCut's stream-copy export is
+// frame-accurate at the boundaries without re-encoding.
+func snapSegmentsToKeyframes(segments []CutSegment, keyframes []float64) []CutSegment {
+	if len(keyframes) == 0 {
+		return segments
+	}
+
+	snapped := make([]CutSegment, len(segments))
+	for i, seg := range segments {
+		snapped[i] = CutSegment{
+			Start: snapDown(keyframes, seg.Start),
+			End:   snapUp(keyframes, seg.End),
+			Name:  seg.Name,
+		}
+	}
+	return snapped
+}
+
+// snapDown returns the largest keyframe timestamp <= t via binary search,
+// or the first keyframe if t precedes all of them.
+func snapDown(keyframes []float64, t float64) float64 {
+	i := sort.Search(len(keyframes), func(i int) bool { return keyframes[i] > t })
+	if i == 0 {
+		return keyframes[0]
+	}
+	return keyframes[i-1]
+}
+
+// snapUp returns the smallest keyframe timestamp >= t via binary search,
+// or the last keyframe if t is past all of them.
+func snapUp(keyframes []float64, t float64) float64 {
+	i := sort.Search(len(keyframes), func(i int) bool { return keyframes[i] >= t })
+	if i == len(keyframes) {
+		return keyframes[len(keyframes)-1]
+	}
+	return keyframes[i]
+}
+
+// loadOrProbeKeyframes returns videoFile's keyframe timestamps, reusing a
+// cached ".keyframes.json" sidecar from a previous run when one exists.
+func loadOrProbeKeyframes(videoFile string) ([]float64, error) {
+	sidecarPath := keyframesSidecarPath(videoFile)
+
+	if keyframes, err := readKeyframesSidecar(sidecarPath); err == nil && len(keyframes) > 0 {
+		log.Printf("Reusing cached keyframe list: %s", sidecarPath)
+		return keyframes, nil
+	}
+
+	keyframes, err := probeKeyframes(videoFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeKeyframesSidecar(sidecarPath, keyframes); err != nil {
+		log.Printf("Failed to write keyframe sidecar %s: %v", sidecarPath, err)
+	}
+
+	return keyframes, nil
+}
+
+func keyframesSidecarPath(videoFile string) string {
+	ext := filepath.Ext(videoFile)
+	return strings.TrimSuffix(videoFile, ext) + ".keyframes.json"
+}
+
+func readKeyframesSidecar(path string) ([]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyframes []float64
+	if err := json.Unmarshal(data, &keyframes); err != nil {
+		return nil, err
+	}
+	return keyframes, nil
+}
+
+func writeKeyframesSidecar(path string, keyframes []float64) error {
+	data, err := json.MarshalIndent(keyframes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// probeKeyframes runs ffprobe to collect the input video's keyframe
+// timestamps into a sorted list. It asks for "pts_time" first — the field
+// name current ffprobe versions use — and falls back to the older
+// "pkt_pts_time" (removed in recent ffprobe, where it just prints "N/A")
+// if that comes back empty, so neither a too-old nor too-new ffprobe
+// silently yields zero keyframes.
+func probeKeyframes(videoFile string) ([]float64, error) {
+	keyframes, err := probeKeyframesField(videoFile, "pts_time")
+	if err != nil {
+		return nil, err
+	}
+	if len(keyframes) == 0 {
+		log.Printf("No keyframes via pts_time, retrying with legacy pkt_pts_time field")
+		keyframes, err = probeKeyframesField(videoFile, "pkt_pts_time")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(keyframes) == 0 {
+		return nil, fmt.Errorf("ffprobe returned no keyframe timestamps for %s", videoFile)
+	}
+
+	return keyframes, nil
+}
+
+// probeKeyframesField runs ffprobe requesting a single frame field and
+// parses each output line as a timestamp, skipping lines ffprobe can't
+// fill in (blank, or "N/A" for a field it doesn't support).
+func probeKeyframesField(videoFile, field string) ([]float64, error) {
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-select_streams", "v",
+		"-show_frames",
+		"-skip_frame", "nokey",
+		"-show_entries", "frame="+field,
+		"-of", "csv=p=0",
+		videoFile,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe error: %v", err)
+	}
+
+	var keyframes []float64
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "N/A" {
+			continue
+		}
+		ts, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			continue
+		}
+		keyframes = append(keyframes, ts)
+	}
+
+	sort.Float64s(keyframes)
+	return keyframes, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+)
+
+// rmsBaselineBlockSize is the median-of-medians block size (in windows)
+// used by robustBaseline. Shared between the streaming and buffered RMS
+// paths so -detector rms behaves identically regardless of -demux.
+const rmsBaselineBlockSize = 60
+
+// streamingRMSMarkers extracts audio via ffmpeg and finds excitement
+// markers with the streaming RMS pipeline, without ever holding the full
+// decoded recording in memory.
+func streamingRMSMarkers(analyzer *AudioAnalyzer, inputFile string, workerCount int) ([]ExcitementMarker, error) {
+	audioFile, err := extractAudio(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(audioFile)
+
+	rmsValues, sampleRate, err := analyzer.StreamWindowedRMS(audioFile, workerCount)
+	if err != nil {
+		return nil, err
+	}
+
+	windowSamples := analyzer.WindowSize
+	if windowSamples <= 0 {
+		windowSamples = int(sampleRate)
+	}
+
+	// Median-of-medians resists being dragged upward by one sustained
+	// loud section, unlike a plain mean.
+	baseline := robustBaseline(rmsValues, rmsBaselineBlockSize)
+	log.Printf("Robust baseline RMS: %.6f", baseline)
+
+	threshold := baseline * analyzer.ThresholdRatio
+	log.Printf("Threshold: %.6f (%.1fx baseline)", threshold, analyzer.ThresholdRatio)
+
+	flagged := make([]bool, len(rmsValues))
+	for i, rms := range rmsValues {
+		flagged[i] = rms > threshold
+	}
+
+	label := func(start, end int) string {
+		return fmt.Sprintf("Excitement (%.1fx)", averageMultiplier(rmsValues, baseline, start, end))
+	}
+	score := func(start, end int) float64 {
+		return averageMultiplier(rmsValues, baseline, start, end)
+	}
+
+	return markersFromFlags(flagged, windowSamples, sampleRate, analyzer.MinDuration, label, score), nil
+}
+
+// streamWindowJob is one decoded, non-overlapping RMS window handed off to
+// a worker, tagged with its position in the stream so the collector can
+// place results back in order.
+type streamWindowJob struct {
+	index   int
+	samples []float64
+}
+
+// streamWindowResult is a worker's computed RMS for one streamWindowJob.
+type streamWindowResult struct {
+	index int
+	rms   float64
+}
+
+// StreamWindowedRMS computes per-window RMS values for audioFile without
+// ever materializing the full decoded sample slab: a single producer
+// goroutine reads one window's worth of PCM at a time from the WAV
+// decoder into a bounded channel, and a pool of workerCount goroutines
+// compute each window's RMS in parallel. Memory use is bounded by the
+// channel depth rather than the recording's length, and wall time scales
+// roughly linearly with available cores.
+func (a *AudioAnalyzer) StreamWindowedRMS(audioFile string, workerCount int) ([]float64, float64, error) {
+	file, err := os.Open(audioFile)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	decoder := wav.NewDecoder(file)
+	if !decoder.IsValidFile() {
+		return nil, 0, fmt.Errorf("invalid WAV file")
+	}
+
+	sampleRate := float64(decoder.SampleRate)
+	a.SampleRate = int(sampleRate)
+
+	windowSamples := a.WindowSize
+	if windowSamples <= 0 {
+		windowSamples = int(sampleRate)
+	}
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+
+	maxValue := math.Pow(2, float64(decoder.BitDepth-1))
+
+	jobs := make(chan streamWindowJob, workerCount*2)
+	results := make(chan streamWindowResult, workerCount*2)
+
+	var workers sync.WaitGroup
+	workers.Add(workerCount)
+	for i := 0; i < workerCount; i++ {
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				results <- streamWindowResult{index: job.index, rms: runningSumOfSquaresRMS(job.samples)}
+			}
+		}()
+	}
+
+	var readErr error
+	go func() {
+		defer close(jobs)
+
+		buf := &audio.IntBuffer{
+			Data:   make([]int, windowSamples),
+			Format: &audio.Format{NumChannels: 1, SampleRate: int(sampleRate)},
+		}
+
+		for index := 0; ; index++ {
+			buf.Data = buf.Data[:cap(buf.Data)]
+			n, err := decoder.PCMBuffer(buf)
+			if err != nil && err != io.EOF {
+				readErr = err
+				return
+			}
+			if n < windowSamples {
+				// Partial or empty trailing window: drop it, matching
+				// the original block-RMS behavior.
+				return
+			}
+
+			window := make([]float64, windowSamples)
+			for i, s := range buf.Data[:n] {
+				window[i] = float64(s) / maxValue
+			}
+			jobs <- streamWindowJob{index: index, samples: window}
+		}
+	}()
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var rmsValues []float64
+	for result := range results {
+		for result.index >= len(rmsValues) {
+			rmsValues = append(rmsValues, 0)
+		}
+		rmsValues[result.index] = result.rms
+	}
+
+	if readErr != nil {
+		return nil, 0, readErr
+	}
+
+	log.Printf("Streamed %d windows with %d workers", len(rmsValues), workerCount)
+
+	return rmsValues, sampleRate, nil
+}
+
+// runningSumOfSquaresRMS computes RMS over samples via a running sum of
+// squares: each sample contributes in O(1), so the whole window is
+// O(len(samples)) with no intermediate allocation.
+func runningSumOfSquaresRMS(samples []float64) float64 {
+	sumOfSquares := 0.0
+	for _, s := range samples {
+		sumOfSquares += s * s
+	}
+	return math.Sqrt(sumOfSquares / float64(len(samples)))
+}
+
+// robustBaseline estimates a volume baseline resistant to sustained loud
+// sections via median-of-medians: rmsValues is split into blocks of
+// blockSize, each block's median is taken, and the median of those block
+// medians is returned. Unlike a plain mean, one long loud section can't
+// drag this upward.
+func robustBaseline(rmsValues []float64, blockSize int) float64 {
+	if len(rmsValues) == 0 {
+		return 0
+	}
+	if blockSize <= 0 {
+		blockSize = 1
+	}
+
+	var blockMedians []float64
+	for start := 0; start < len(rmsValues); start += blockSize {
+		end := start + blockSize
+		if end > len(rmsValues) {
+			end = len(rmsValues)
+		}
+		blockMedians = append(blockMedians, median(rmsValues[start:end]))
+	}
+
+	return median(blockMedians)
+}
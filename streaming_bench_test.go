@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// benchSampleRate and benchDurationSeconds describe the synthetic WAV fixture
+// used below: one hour at a typical extraction rate, matching the scale the
+// streaming path exists for (buffering it whole would hold ~1.3GB of
+// []float64 samples in memory).
+const (
+	benchSampleRate      = 44100
+	benchDurationSeconds = 3600
+)
+
+// writeSyntheticWAV generates a mono 16-bit PCM WAV file of the given
+// duration filled with a tone plus intermittent loud bursts, so RMS-based
+// detectors have something to flag. It writes samples directly with
+// encoding/binary rather than pulling in an encoder, since the repo only
+// depends on go-audio for decoding.
+func writeSyntheticWAV(t *testing.B, path string, sampleRate, durationSeconds int) {
+	t.Helper()
+
+	numSamples := sampleRate * durationSeconds
+	dataBytes := numSamples * 2
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create fixture: %v", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, 44)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+dataBytes))
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:24], 1) // mono
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(sampleRate*2))
+	binary.LittleEndian.PutUint16(header[32:34], 2)
+	binary.LittleEndian.PutUint16(header[34:36], 16)
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(dataBytes))
+	if _, err := file.Write(header); err != nil {
+		t.Fatalf("write fixture header: %v", err)
+	}
+
+	const chunkSamples = 1 << 16
+	chunk := make([]byte, chunkSamples*2)
+	for written := 0; written < numSamples; written += chunkSamples {
+		n := chunkSamples
+		if written+n > numSamples {
+			n = numSamples - written
+		}
+		for i := 0; i < n; i++ {
+			t := float64(written+i) / float64(sampleRate)
+			amplitude := 0.05
+			if int(t)%20 < 2 {
+				amplitude = 0.8 // a loud burst every 20s
+			}
+			sample := int16(amplitude * math.Sin(2*math.Pi*440*t) * math.MaxInt16)
+			binary.LittleEndian.PutUint16(chunk[i*2:i*2+2], uint16(sample))
+		}
+		if _, err := file.Write(chunk[:n*2]); err != nil {
+			t.Fatalf("write fixture samples: %v", err)
+		}
+	}
+}
+
+// BenchmarkStreamWindowedRMS measures the streaming RMS pipeline
+// (StreamWindowedRMS) against a synthetic one-hour recording, so its
+// throughput can be compared directly against BenchmarkBufferedRMS below.
+func BenchmarkStreamWindowedRMS(b *testing.B) {
+	audioFile := filepath.Join(b.TempDir(), "bench.wav")
+	writeSyntheticWAV(b, audioFile, benchSampleRate, benchDurationSeconds)
+
+	analyzer := &AudioAnalyzer{WindowSize: benchSampleRate / 4, ThresholdRatio: 2.0, MinDuration: 1.0}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := analyzer.StreamWindowedRMS(audioFile, runtime.NumCPU()); err != nil {
+			b.Fatalf("StreamWindowedRMS: %v", err)
+		}
+	}
+}
+
+// BenchmarkBufferedRMS measures the original implementation: LoadAudio reads
+// the whole recording into a []float64 before RMSDetector.Analyze runs over
+// it, so this is the "current implementation" the streaming path is
+// benchmarked against.
+func BenchmarkBufferedRMS(b *testing.B) {
+	audioFile := filepath.Join(b.TempDir(), "bench.wav")
+	writeSyntheticWAV(b, audioFile, benchSampleRate, benchDurationSeconds)
+
+	analyzer := &AudioAnalyzer{WindowSize: benchSampleRate / 4, ThresholdRatio: 2.0, MinDuration: 1.0}
+	detector := &RMSDetector{WindowSize: analyzer.WindowSize, ThresholdRatio: analyzer.ThresholdRatio, MinDuration: analyzer.MinDuration}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		samples, sampleRate, err := analyzer.LoadAudio(audioFile)
+		if err != nil {
+			b.Fatalf("LoadAudio: %v", err)
+		}
+		detector.Analyze(samples, sampleRate)
+	}
+}
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 func main() {
@@ -33,7 +39,21 @@ func main() {
 			return
 		}
 
-		filePath := filepath.Join(absPath, strings.TrimPrefix(r.URL.Path, "/"))
+		urlPath := strings.TrimPrefix(r.URL.Path, "/")
+
+		if idx := strings.Index(urlPath, "/hls/"); idx != -1 {
+			videoRelPath := urlPath[:idx]
+			hlsPath := urlPath[idx+len("/hls/"):]
+			handleHLSRequest(w, r, videoRelPath, hlsPath)
+			return
+		}
+
+		if r.URL.Query().Get("stream") == "hls" {
+			handleHLSPlaylistRequest(w, r, absPath, urlPath)
+			return
+		}
+
+		filePath := filepath.Join(absPath, urlPath)
 		serveFileWithRangeSupport(w, r, filePath)
 	})
 
@@ -169,15 +189,324 @@ func serveFileWithRangeSupport(w http.ResponseWriter, r *http.Request, filePath
 			log.Printf("Failed to copy file range: %v", err)
 		}
 	} else {
-		// Multiple ranges not supported for simplicity
-		http.Error(w, "Multiple ranges not supported", http.StatusRequestedRangeNotSatisfiable)
+		serveMultipartRanges(w, file, ranges, fileSize, contentType)
+	}
+}
+
+// serveMultipartRanges writes an RFC 7233 multipart/byteranges response,
+// one part per requested range, each with its own Content-Type and
+// Content-Range header.
+func serveMultipartRanges(w http.ResponseWriter, file *os.File, ranges []httpRange, fileSize int64, contentType string) {
+	boundary, err := randomHexPrefix()
+	if err != nil {
+		http.Error(w, "Failed to prepare multipart response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", fmt.Sprintf("multipart/byteranges; boundary=%s", boundary))
+	w.WriteHeader(http.StatusPartialContent)
+
+	for _, rg := range ranges {
+		fmt.Fprintf(w, "--%s\r\n", boundary)
+		fmt.Fprintf(w, "Content-Type: %s\r\n", contentType)
+		fmt.Fprintf(w, "Content-Range: bytes %d-%d/%d\r\n\r\n", rg.start, rg.end, fileSize)
+
+		if _, err := file.Seek(rg.start, 0); err != nil {
+			log.Printf("Failed to seek file: %v", err)
+			return
+		}
+		if _, err := io.CopyN(w, file, rg.end-rg.start+1); err != nil {
+			log.Printf("Failed to copy file range: %v", err)
+			return
+		}
+		fmt.Fprint(w, "\r\n")
+	}
+
+	fmt.Fprintf(w, "--%s--\r\n", boundary)
+}
+
+const (
+	hlsSegmentSeconds = 6
+	hlsSessionTTL     = 15 * time.Minute
+)
+
+// hlsSegment describes one fragment of a lazily-transcoded HLS stream.
+type hlsSegment struct {
+	name     string
+	duration float64
+}
+
+// hlsSession tracks the on-disk segments produced for a single streaming
+// session so repeat playlist/segment requests can be served without
+// re-transcoding.
+type hlsSession struct {
+	prefix     string
+	sourcePath string
+	dir        string
+	segments   []hlsSegment
+	timer      *time.Timer
+}
+
+var (
+	hlsSessionsMu sync.Mutex
+	hlsSessions   = map[string]*hlsSession{} // keyed by session prefix, for serving "/hls/" requests
+	hlsBySource   = map[string]*hlsSession{} // keyed by sourcePath, so repeat requests reuse segments
+)
+
+// handleHLSPlaylistRequest responds with the HLS playlist for the video at
+// videoRelPath (relative to absPath), reusing an existing streaming session
+// for that source file if one is already segmented and otherwise starting a
+// new one.
+func handleHLSPlaylistRequest(w http.ResponseWriter, r *http.Request, absPath, videoRelPath string) {
+	sourcePath := filepath.Join(absPath, videoRelPath)
+	if stat, err := os.Stat(sourcePath); err != nil || stat.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	hlsSessionsMu.Lock()
+	session, ok := hlsBySource[sourcePath]
+	if ok {
+		// Reusing an existing session still counts as activity against it,
+		// so a session a client keeps streaming from never expires
+		// mid-playback.
+		session.timer.Reset(hlsSessionTTL)
+	}
+	hlsSessionsMu.Unlock()
+	if ok {
+		writePlaylist(w, videoRelPath, session.prefix, session.segments)
+		return
+	}
+
+	prefix, err := randomHexPrefix()
+	if err != nil {
+		log.Printf("Failed to generate session prefix: %v", err)
+		http.Error(w, "Failed to start stream session", http.StatusInternalServerError)
+		return
+	}
+
+	sessionDir := filepath.Join(os.TempDir(), "hls-sessions", prefix)
+	segments, err := segmentToHLS(sourcePath, sessionDir, prefix)
+	if err != nil {
+		log.Printf("Failed to segment %s: %v", sourcePath, err)
+		os.RemoveAll(sessionDir)
+		http.Error(w, "Failed to prepare stream", http.StatusInternalServerError)
+		return
+	}
+
+	session = &hlsSession{prefix: prefix, sourcePath: sourcePath, dir: sessionDir, segments: segments}
+	session.timer = time.AfterFunc(hlsSessionTTL, func() { expireHLSSession(prefix) })
+
+	hlsSessionsMu.Lock()
+	hlsSessions[prefix] = session
+	hlsBySource[sourcePath] = session
+	hlsSessionsMu.Unlock()
+
+	writePlaylist(w, videoRelPath, prefix, segments)
+}
+
+// handleHLSRequest serves a playlist or segment file belonging to an
+// existing streaming session. hlsPath is everything after ".../hls/", e.g.
+// "a1b2c3d4e5f6.m3u8" or "a1b2c3d4e5f6_seg000.ts".
+func handleHLSRequest(w http.ResponseWriter, r *http.Request, videoRelPath, hlsPath string) {
+	prefix := strings.TrimSuffix(hlsPath, ".m3u8")
+	if idx := strings.Index(prefix, "_seg"); idx != -1 {
+		prefix = prefix[:idx]
+	}
+
+	hlsSessionsMu.Lock()
+	session, ok := hlsSessions[prefix]
+	if ok {
+		session.timer.Reset(hlsSessionTTL)
+	}
+	hlsSessionsMu.Unlock()
+	if !ok {
+		http.Error(w, "Stream session expired or not found", http.StatusGone)
+		return
+	}
+
+	if strings.HasSuffix(hlsPath, ".m3u8") {
+		writePlaylist(w, videoRelPath, prefix, session.segments)
+		return
+	}
+
+	segPath, ok := safeJoin(session.dir, hlsPath)
+	if !ok {
+		http.Error(w, "Invalid segment path", http.StatusBadRequest)
+		return
+	}
+	serveFileWithRangeSupport(w, r, segPath)
+}
+
+// safeJoin joins dir and name and reports whether the cleaned result is
+// still inside dir, rejecting a name (e.g. containing "..") that would
+// otherwise let a request escape the session directory.
+func safeJoin(dir, name string) (string, bool) {
+	joined := filepath.Join(dir, name)
+	if joined != dir && !strings.HasPrefix(joined, dir+string(os.PathSeparator)) {
+		return "", false
+	}
+	return joined, true
+}
+
+// writePlaylist renders the #EXTM3U manifest for a session's segments.
+func writePlaylist(w http.ResponseWriter, videoRelPath, prefix string, segments []hlsSegment) {
+	targetDuration := hlsSegmentSeconds
+	for _, seg := range segments {
+		if int(seg.duration+0.999) > targetDuration {
+			targetDuration = int(seg.duration + 0.999)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n", targetDuration)
+	for _, seg := range segments {
+		fmt.Fprintf(w, "#EXTINF:%.3f,\n%s/hls/%s\n", seg.duration, videoRelPath, seg.name)
+	}
+	fmt.Fprint(w, "#EXT-X-ENDLIST\n")
+}
+
+// segmentToHLS fragments sourcePath into MPEG-TS segments under sessionDir.
+// It tries a stream copy first: remuxing is a fraction of the cost of a
+// libx264/aac re-encode, which matters since a client is waiting on this
+// call before it can start playback at all. If the source codecs aren't
+// segment/TS-compatible, ffmpeg's stream-copy attempt fails fast and we fall
+// back to a full re-encode.
+func segmentToHLS(sourcePath, sessionDir, prefix string) ([]hlsSegment, error) {
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		return nil, err
+	}
+
+	listPath := filepath.Join(sessionDir, prefix+"_list.txt")
+	segmentPattern := filepath.Join(sessionDir, prefix+"_seg%03d.ts")
+
+	copyArgs := []string{
+		"-i", sourcePath,
+		"-c", "copy",
+		"-f", "segment",
+		"-segment_time", strconv.Itoa(hlsSegmentSeconds),
+		"-segment_list", listPath,
+		"-segment_list_type", "flat",
+		"-reset_timestamps", "1",
+		"-y",
+		segmentPattern,
+	}
+	output, err := exec.Command("ffmpeg", copyArgs...).CombinedOutput()
+	if err != nil {
+		log.Printf("Stream-copy segmenting failed for %s, falling back to re-encode: %v", sourcePath, err)
+
+		encodeArgs := []string{
+			"-i", sourcePath,
+			"-c:v", "libx264",
+			"-c:a", "aac",
+			"-f", "segment",
+			"-segment_time", strconv.Itoa(hlsSegmentSeconds),
+			"-segment_list", listPath,
+			"-segment_list_type", "flat",
+			"-reset_timestamps", "1",
+			"-y",
+			segmentPattern,
+		}
+		output, err = exec.Command("ffmpeg", encodeArgs...).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg error: %v\nOutput: %s", err, string(output))
+		}
+	}
+
+	names, err := readSegmentList(listPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no segments produced for %s", sourcePath)
+	}
+
+	segments := make([]hlsSegment, len(names))
+	for i, name := range names {
+		segPath := filepath.Join(sessionDir, name)
+		duration, err := probeDuration(segPath)
+		if err != nil {
+			log.Printf("Failed to probe duration of segment %s, assuming %ds: %v", name, hlsSegmentSeconds, err)
+			duration = hlsSegmentSeconds
+		}
+		segments[i] = hlsSegment{name: name, duration: duration}
+	}
+
+	return segments, nil
+}
+
+// readSegmentList reads the plain-text, one-filename-per-line list produced
+// by ffmpeg's "-segment_list_type flat".
+func readSegmentList(listPath string) ([]string, error) {
+	data, err := os.ReadFile(listPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// probeDuration returns the duration in seconds of mediaFile via ffprobe.
+func probeDuration(mediaFile string) (float64, error) {
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		mediaFile,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe error: %v", err)
+	}
+
+	return strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+}
+
+// expireHLSSession removes a streaming session's cached segments once its
+// TTL has elapsed so stale segments are never reused by a later session.
+func expireHLSSession(prefix string) {
+	hlsSessionsMu.Lock()
+	session, ok := hlsSessions[prefix]
+	delete(hlsSessions, prefix)
+	if ok {
+		delete(hlsBySource, session.sourcePath)
+	}
+	hlsSessionsMu.Unlock()
+
+	if ok {
+		if err := os.RemoveAll(session.dir); err != nil {
+			log.Printf("Failed to clean up HLS session %s: %v", prefix, err)
+		}
+	}
+}
+
+// randomHexPrefix generates a 6-byte random hex string used to namespace a
+// streaming session's segment files so stale segments are never reused.
+func randomHexPrefix() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(b), nil
 }
 
 type httpRange struct {
 	start, end int64
 }
 
+// parseRange parses an RFC 7233 Range header into a list of byte ranges.
+// Per spec, individual unsatisfiable ranges (e.g. a prefix starting past
+// EOF) are dropped rather than failing the whole header, ranges that
+// extend past EOF are coalesced down to the last byte of the file, and a
+// 416 is only warranted when *no* range in the header is satisfiable.
 func parseRange(rangeHeader string, fileSize int64) ([]httpRange, error) {
 	if !strings.HasPrefix(rangeHeader, "bytes=") {
 		return nil, fmt.Errorf("invalid range header")
@@ -190,50 +519,76 @@ func parseRange(rangeHeader string, fileSize int64) ([]httpRange, error) {
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
 
-		if strings.HasPrefix(part, "-") {
-			// Suffix range
+		var start, end int64
+
+		switch {
+		case strings.HasPrefix(part, "-"):
+			// Suffix range: last N bytes.
 			suffix, err := strconv.ParseInt(part[1:], 10, 64)
-			if err != nil {
-				return nil, err
+			if err != nil || suffix <= 0 {
+				continue
 			}
-			start := fileSize - suffix
-			if start < 0 {
-				start = 0
+			if suffix > fileSize {
+				suffix = fileSize
 			}
-			ranges = append(ranges, httpRange{start, fileSize - 1})
-		} else if strings.HasSuffix(part, "-") {
-			// Prefix range
-			start, err := strconv.ParseInt(part[:len(part)-1], 10, 64)
-			if err != nil {
-				return nil, err
+			start, end = fileSize-suffix, fileSize-1
+
+		case strings.HasSuffix(part, "-"):
+			// Prefix range: from N to EOF.
+			s, err := strconv.ParseInt(part[:len(part)-1], 10, 64)
+			if err != nil || s >= fileSize {
+				continue
 			}
-			ranges = append(ranges, httpRange{start, fileSize - 1})
-		} else {
-			// Full range
-			rangeParts := strings.Split(part, "-")
+			start, end = s, fileSize-1
+
+		default:
+			// Full range: N-M, coalesced down to EOF if M overshoots.
+			rangeParts := strings.SplitN(part, "-", 2)
 			if len(rangeParts) != 2 {
-				return nil, fmt.Errorf("invalid range format")
+				continue
 			}
 
-			start, err := strconv.ParseInt(rangeParts[0], 10, 64)
-			if err != nil {
-				return nil, err
+			s, errStart := strconv.ParseInt(rangeParts[0], 10, 64)
+			e, errEnd := strconv.ParseInt(rangeParts[1], 10, 64)
+			if errStart != nil || errEnd != nil || s > e || s >= fileSize {
+				continue
 			}
-
-			end, err := strconv.ParseInt(rangeParts[1], 10, 64)
-			if err != nil {
-				return nil, err
+			if e >= fileSize {
+				e = fileSize - 1
 			}
+			start, end = s, e
+		}
 
-			if start > end || end >= fileSize {
-				return nil, fmt.Errorf("invalid range values")
-			}
+		ranges = append(ranges, httpRange{start, end})
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no satisfiable ranges")
+	}
 
-			ranges = append(ranges, httpRange{start, end})
+	return coalesceRanges(ranges), nil
+}
+
+// coalesceRanges sorts ranges by start offset and merges any that overlap
+// or are adjacent (one starts where the previous ends, byte-for-byte) into
+// a single range, per RFC 7233's recommendation that a server MAY combine
+// such ranges to avoid sending the same bytes twice.
+func coalesceRanges(ranges []httpRange) []httpRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	merged := ranges[:1]
+	for _, rg := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if rg.start <= last.end+1 {
+			if rg.end > last.end {
+				last.end = rg.end
+			}
+			continue
 		}
+		merged = append(merged, rg)
 	}
 
-	return ranges, nil
+	return merged
 }
 
 func getContentType(fileName string) string {
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRangeSuffix(t *testing.T) {
+	ranges, err := parseRange("bytes=-500", 1000)
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	want := []httpRange{{start: 500, end: 999}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got %v, want %v", ranges, want)
+	}
+}
+
+func TestParseRangeSuffixLargerThanFile(t *testing.T) {
+	ranges, err := parseRange("bytes=-5000", 1000)
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	want := []httpRange{{start: 0, end: 999}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got %v, want %v", ranges, want)
+	}
+}
+
+func TestParseRangePrefix(t *testing.T) {
+	ranges, err := parseRange("bytes=900-", 1000)
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	want := []httpRange{{start: 900, end: 999}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got %v, want %v", ranges, want)
+	}
+}
+
+func TestParseRangeMixed(t *testing.T) {
+	ranges, err := parseRange("bytes=0-99,900-,-50", 1000)
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	want := []httpRange{
+		{start: 0, end: 99},
+		{start: 900, end: 999}, // prefix 900- and suffix -50 (950-999) coalesce
+	}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got %v, want %v", ranges, want)
+	}
+}
+
+func TestParseRangeOverlappingCoalesced(t *testing.T) {
+	ranges, err := parseRange("bytes=0-199,100-299", 1000)
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	want := []httpRange{{start: 0, end: 299}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got %v, want %v", ranges, want)
+	}
+}
+
+func TestParseRangeAdjacentCoalesced(t *testing.T) {
+	ranges, err := parseRange("bytes=0-99,100-199", 1000)
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	want := []httpRange{{start: 0, end: 199}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got %v, want %v", ranges, want)
+	}
+}
+
+func TestParseRangeDisjointNotCoalesced(t *testing.T) {
+	ranges, err := parseRange("bytes=0-99,200-299", 1000)
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	want := []httpRange{{start: 0, end: 99}, {start: 200, end: 299}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got %v, want %v", ranges, want)
+	}
+}
+
+func TestParseRangeUnsatisfiableDropped(t *testing.T) {
+	// The first range starts past EOF and is dropped; the second is kept.
+	ranges, err := parseRange("bytes=2000-2100,0-99", 1000)
+	if err != nil {
+		t.Fatalf("parseRange: %v", err)
+	}
+	want := []httpRange{{start: 0, end: 99}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got %v, want %v", ranges, want)
+	}
+}
+
+func TestParseRangeAllUnsatisfiable(t *testing.T) {
+	if _, err := parseRange("bytes=2000-2100", 1000); err == nil {
+		t.Error("expected error for entirely unsatisfiable range header")
+	}
+}
+
+func TestParseRangeInvalidHeader(t *testing.T) {
+	if _, err := parseRange("not-a-range-header", 1000); err == nil {
+		t.Error("expected error for malformed range header")
+	}
+}